@@ -0,0 +1,103 @@
+package caldav
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/emersion/go-ical"
+)
+
+func TestSetAttachProperty(t *testing.T) {
+	event := ical.NewEvent()
+	comp := event.Component
+
+	SetAttachProperty(comp, "managed-1", "https://example.com/attachments/1", "text/plain", "notes.txt", 42)
+
+	props := comp.Props[ical.PropAttach]
+	if len(props) != 1 {
+		t.Fatalf("expected 1 ATTACH property, got %d", len(props))
+	}
+	prop := props[0]
+	if prop.Value != "https://example.com/attachments/1" {
+		t.Errorf("unexpected ATTACH value: %q", prop.Value)
+	}
+	if got := prop.Params.Get("MANAGED-ID"); got != "managed-1" {
+		t.Errorf("unexpected MANAGED-ID: %q", got)
+	}
+	if got := prop.Params.Get("FMTTYPE"); got != "text/plain" {
+		t.Errorf("unexpected FMTTYPE: %q", got)
+	}
+	if got := prop.Params.Get("FILENAME"); got != "notes.txt" {
+		t.Errorf("unexpected FILENAME: %q", got)
+	}
+	if got := prop.Params.Get("SIZE"); got != "42" {
+		t.Errorf("unexpected SIZE: %q", got)
+	}
+
+	// Re-adding with the same MANAGED-ID replaces the existing property
+	// rather than appending a second one.
+	SetAttachProperty(comp, "managed-1", "https://example.com/attachments/1-v2", "text/plain", "notes.txt", 43)
+	props = comp.Props[ical.PropAttach]
+	if len(props) != 1 {
+		t.Fatalf("expected replace, got %d ATTACH properties", len(props))
+	}
+	if props[0].Value != "https://example.com/attachments/1-v2" {
+		t.Errorf("expected updated ATTACH value, got %q", props[0].Value)
+	}
+}
+
+func TestRemoveAttachProperty(t *testing.T) {
+	event := ical.NewEvent()
+	comp := event.Component
+
+	SetAttachProperty(comp, "managed-1", "https://example.com/attachments/1", "", "", 1)
+	SetAttachProperty(comp, "managed-2", "https://example.com/attachments/2", "", "", 2)
+
+	RemoveAttachProperty(comp, "managed-1")
+
+	props := comp.Props[ical.PropAttach]
+	if len(props) != 1 {
+		t.Fatalf("expected 1 ATTACH property remaining, got %d", len(props))
+	}
+	if got := props[0].Params.Get("MANAGED-ID"); got != "managed-2" {
+		t.Errorf("expected managed-2 to remain, got %q", got)
+	}
+}
+
+type attachmentTestBackend struct {
+	testBackend
+	added string
+}
+
+func (b *attachmentTestBackend) AddAttachment(ctx context.Context, objectPath string, att Attachment) (*CalendarObject, string, error) {
+	b.added = objectPath
+	return &CalendarObject{Path: objectPath}, "managed-1", nil
+}
+
+func (b *attachmentTestBackend) UpdateAttachment(ctx context.Context, objectPath, managedID string, att Attachment) (*CalendarObject, error) {
+	return &CalendarObject{Path: objectPath}, nil
+}
+
+func (b *attachmentTestBackend) RemoveAttachment(ctx context.Context, objectPath, managedID string) (*CalendarObject, error) {
+	return &CalendarObject{Path: objectPath}, nil
+}
+
+func TestHandleAttachmentAction(t *testing.T) {
+	backend := &attachmentTestBackend{}
+
+	obj, err := HandleAttachmentAction(nil, backend, "/user/calendars/cal/event.ics", url.Values{"action": {AttachmentActionAdd}}, Attachment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.Path != "/user/calendars/cal/event.ics" {
+		t.Errorf("unexpected object path: %q", obj.Path)
+	}
+	if backend.added != "/user/calendars/cal/event.ics" {
+		t.Errorf("AddAttachment was not called with the right object path")
+	}
+
+	if _, err := HandleAttachmentAction(nil, backend, "/user/calendars/cal/event.ics", url.Values{"action": {AttachmentActionUpdate}}, Attachment{}); err == nil {
+		t.Error("expected an error for attachment-update without a managed-id")
+	}
+}
@@ -0,0 +1,99 @@
+// Package caldav implements the CalDAV protocol, as defined in RFC 4791.
+package caldav
+
+import (
+	"time"
+
+	"github.com/emersion/go-ical"
+)
+
+// Calendar represents a calendar collection.
+type Calendar struct {
+	Path                  string
+	Name                  string
+	Description           string
+	MaxResourceSize       int64
+	SupportedComponentSet []string
+	Timezone              string
+	Color                 string
+}
+
+// CalendarCompRequest describes which components and properties a client
+// is interested in, as indicated by a <C:calendar-data> element.
+type CalendarCompRequest struct {
+	Name     string
+	Props    []string
+	AllProps bool
+	Comps    []CalendarCompRequest
+	AllComps bool
+}
+
+// CalendarObject represents a calendar object resource, e.g. an event,
+// to-do or journal entry.
+type CalendarObject struct {
+	Path          string
+	ModTime       time.Time
+	ContentLength int64
+	ETag          string
+	Data          *ical.Calendar
+}
+
+// PutCalendarObjectOptions holds optional parameters for
+// Backend.PutCalendarObject, e.g. conditional-PUT preconditions.
+type PutCalendarObjectOptions struct {
+	IfNoneMatch bool
+	IfMatch     string
+}
+
+// CompFilter corresponds to a <C:comp-filter> element, as defined in RFC
+// 4791 section 9.7.1.
+type CompFilter struct {
+	Name         string
+	IsNotDefined bool
+	TimeRange    *TimeRange
+	Props        []PropFilter
+	Comps        []CompFilter
+}
+
+// PropFilter corresponds to a <C:prop-filter> element.
+type PropFilter struct {
+	Name         string
+	IsNotDefined bool
+	TimeRange    *TimeRange
+	TextMatch    *TextMatch
+	Params       []ParamFilter
+}
+
+// ParamFilter corresponds to a <C:param-filter> element.
+type ParamFilter struct {
+	Name         string
+	IsNotDefined bool
+	TextMatch    *TextMatch
+}
+
+// TextMatch corresponds to a <C:text-match> element.
+type TextMatch struct {
+	Text            string
+	Collation       string
+	NegateCondition bool
+}
+
+// TimeRange corresponds to a <C:time-range> element. Start and End are
+// always in UTC, per RFC 4791 section 9.9.
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// CalendarQuery represents a parsed calendar-query REPORT request, as
+// defined in RFC 4791 section 7.8.
+type CalendarQuery struct {
+	CompFilter  CompFilter
+	CompRequest CalendarCompRequest
+}
+
+// CalendarMultiGet represents a parsed calendar-multiget REPORT request.
+type CalendarMultiGet struct {
+	Paths       []string
+	CompRequest CalendarCompRequest
+}
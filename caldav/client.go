@@ -0,0 +1,78 @@
+package caldav
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+
+	"github.com/emersion/go-webdav/internal"
+)
+
+// HTTPClient performs HTTP requests. It's implemented by *http.Client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client performs CalDAV operations against a single server, identified
+// by its calendar home set (or any URL below it).
+type Client struct {
+	ic *internal.Client
+}
+
+// NewClient creates a new Client targeting endpoint. A nil httpClient
+// uses http.DefaultClient.
+func NewClient(httpClient HTTPClient, endpoint string) (*Client, error) {
+	ic, err := internal.NewClient(httpClient, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{ic: ic}, nil
+}
+
+// FindCurrentUserPrincipal returns the path of the current user's
+// principal resource, per RFC 5397.
+func (c *Client) FindCurrentUserPrincipal(ctx context.Context) (string, error) {
+	propfind := internal.NewPropNamePropFind(internal.CurrentUserPrincipalName)
+
+	resp, err := c.ic.PropFindFlat(ctx, "", propfind)
+	if err != nil {
+		return "", err
+	}
+
+	var prop internal.CurrentUserPrincipal
+	if err := resp.DecodeProp(&prop); err != nil {
+		return "", err
+	}
+	if prop.Unauthenticated != nil {
+		return "", fmt.Errorf("caldav: unauthenticated")
+	}
+
+	return prop.Href.Path, nil
+}
+
+var calendarHomeSetName = xml.Name{Space: "urn:ietf:params:xml:ns:caldav", Local: "calendar-home-set"}
+
+type calendarHomeSet struct {
+	XMLName xml.Name      `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set"`
+	Href    internal.Href `xml:"href"`
+}
+
+// FindCalendarHomeSet returns the calendar home set path for the
+// principal at principalPath, resolved against the client's endpoint so
+// it's always a full path even if the server returned a relative href.
+func (c *Client) FindCalendarHomeSet(ctx context.Context, principalPath string) (string, error) {
+	propfind := internal.NewPropNamePropFind(calendarHomeSetName)
+
+	resp, err := c.ic.PropFindFlat(ctx, principalPath, propfind)
+	if err != nil {
+		return "", err
+	}
+
+	var prop calendarHomeSet
+	if err := resp.DecodeProp(&prop); err != nil {
+		return "", err
+	}
+
+	return c.ic.ResolveHref(prop.Href.Path).Path, nil
+}
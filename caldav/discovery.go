@@ -0,0 +1,138 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ServiceEndpoint is the result of CalDAV discovery: the calendar home
+// collection a client should use once it knows nothing but a user's
+// domain and credentials, per RFC 6764. CalendarHomeSet is an absolute
+// URL (resolved against the discovered server's scheme and host), ready
+// to pass straight to NewClient.
+type ServiceEndpoint struct {
+	URL                  *url.URL
+	CurrentUserPrincipal string
+	CalendarHomeSet      string
+}
+
+// Discover resolves the CalDAV service endpoint for domain following RFC
+// 6764: it tries _caldavs._tcp.<domain> and _caldav._tcp.<domain> SRV
+// records first (honoring a TXT "path=" record for the context path),
+// falls back to https://<domain>/.well-known/caldav, and finally
+// PROPFINDs for current-user-principal and calendar-home-set against
+// whatever base URL it found.
+func Discover(ctx context.Context, httpClient HTTPClient, domain string) (*ServiceEndpoint, error) {
+	resolver := net.DefaultResolver
+
+	base, err := discoverSRV(ctx, resolver, domain)
+	if err != nil || base == nil {
+		base = &url.URL{Scheme: "https", Host: domain, Path: "/.well-known/caldav"}
+	}
+
+	return resolveEndpoint(ctx, httpClient, base)
+}
+
+// discoverSRV looks up _caldavs._tcp.<domain> and _caldav._tcp.<domain>,
+// preferring the TLS variant, and applies any "path=" TXT record found
+// for the same name.
+func discoverSRV(ctx context.Context, resolver *net.Resolver, domain string) (*url.URL, error) {
+	for _, svc := range []struct {
+		name   string
+		scheme string
+	}{
+		{"_caldavs._tcp", "https"},
+		{"_caldav._tcp", "http"},
+	} {
+		_, addrs, err := resolver.LookupSRV(ctx, "", "", svc.name+"."+domain)
+		if err != nil || len(addrs) == 0 {
+			continue
+		}
+
+		target := strings.TrimSuffix(addrs[0].Target, ".")
+		host := fmt.Sprintf("%s:%d", target, addrs[0].Port)
+
+		path := "/"
+		if txts, err := resolver.LookupTXT(ctx, svc.name+"."+domain); err == nil {
+			for _, txt := range txts {
+				if strings.HasPrefix(txt, "path=") {
+					path = strings.TrimPrefix(txt, "path=")
+				}
+			}
+		}
+
+		return &url.URL{Scheme: svc.scheme, Host: host, Path: path}, nil
+	}
+	return nil, nil
+}
+
+// resolveEndpoint follows redirects from base (typically the
+// well-known URI) and then PROPFINDs for current-user-principal and
+// calendar-home-set, using the caller's httpClient throughout so that
+// any auth the caller configured (e.g. Basic, a bearer token RoundTripper)
+// applies to every request, not just the final PROPFINDs.
+func resolveEndpoint(ctx context.Context, httpClient HTTPClient, base *url.URL) (*ServiceEndpoint, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	resolved := resp.Request.URL
+
+	client, err := NewClient(httpClient, resolved.String())
+	if err != nil {
+		return nil, err
+	}
+
+	principal, err := client.FindCurrentUserPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	homeSetPath, err := client.FindCalendarHomeSet(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	// FindCalendarHomeSet only returns a path: resolve it against the
+	// discovered server's scheme and host so it's a usable endpoint for
+	// NewClient, rather than a bare path with no host.
+	homeSet := &url.URL{Scheme: resolved.Scheme, Host: resolved.Host, Path: homeSetPath}
+
+	return &ServiceEndpoint{
+		URL:                  resolved,
+		CurrentUserPrincipal: principal,
+		CalendarHomeSet:      homeSet.String(),
+	}, nil
+}
+
+// NewClientWithDiscovery bootstraps a Client from just a user's email
+// address: the part after the "@" is treated as the domain to run RFC
+// 6764 Discover against, and the resulting calendar-home-set becomes the
+// Client's base URL.
+func NewClientWithDiscovery(ctx context.Context, httpClient HTTPClient, email string) (*Client, error) {
+	parts := strings.SplitN(email, "@", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("caldav: %q is not a valid email address", email)
+	}
+	domain := parts[1]
+
+	endpoint, err := Discover(ctx, httpClient, domain)
+	if err != nil {
+		return nil, fmt.Errorf("caldav: discovery failed for %s: %w", domain, err)
+	}
+
+	return NewClient(httpClient, endpoint.CalendarHomeSet)
+}
@@ -0,0 +1,92 @@
+package caldav
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+const discoveryCurrentUserPrincipalResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<D:multistatus xmlns:D="DAV:">
+  <D:response>
+    <D:href>/</D:href>
+    <D:propstat>
+      <D:prop>
+        <D:current-user-principal><D:href>/principals/user/</D:href></D:current-user-principal>
+      </D:prop>
+      <D:status>HTTP/1.1 200 OK</D:status>
+    </D:propstat>
+  </D:response>
+</D:multistatus>
+`
+
+const discoveryCalendarHomeSetResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response>
+    <D:href>/principals/user/</D:href>
+    <D:propstat>
+      <D:prop>
+        <C:calendar-home-set><D:href>/calendars/user/</D:href></C:calendar-home-set>
+      </D:prop>
+      <D:status>HTTP/1.1 200 OK</D:status>
+    </D:propstat>
+  </D:response>
+</D:multistatus>
+`
+
+// newDiscoveryTestServer serves the PROPFIND round trips resolveEndpoint
+// performs: a root PROPFIND for current-user-principal, followed by a
+// PROPFIND against the returned principal for calendar-home-set.
+func newDiscoveryTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusOK)
+		case r.Method == "PROPFIND" && r.URL.Path == "/":
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusMultiStatus)
+			w.Write([]byte(discoveryCurrentUserPrincipalResponse))
+		case r.Method == "PROPFIND" && r.URL.Path == "/principals/user/":
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusMultiStatus)
+			w.Write([]byte(discoveryCalendarHomeSetResponse))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	srv := newDiscoveryTestServer(t)
+	defer srv.Close()
+
+	base, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint, err := resolveEndpoint(context.Background(), srv.Client(), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if endpoint.CurrentUserPrincipal != "/principals/user/" {
+		t.Errorf("unexpected CurrentUserPrincipal: %q", endpoint.CurrentUserPrincipal)
+	}
+
+	want := base.Scheme + "://" + base.Host + "/calendars/user/"
+	if endpoint.CalendarHomeSet != want {
+		t.Errorf("unexpected CalendarHomeSet: got %q, want %q", endpoint.CalendarHomeSet, want)
+	}
+}
+
+func TestNewClientWithDiscoveryInvalidEmail(t *testing.T) {
+	if _, err := NewClientWithDiscovery(context.Background(), nil, "not-an-email"); err == nil {
+		t.Error("expected an error for an email address without an '@'")
+	}
+}
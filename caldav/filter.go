@@ -0,0 +1,429 @@
+package caldav
+
+import (
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/teambition/rrule-go"
+)
+
+// Expand corresponds to a <C:expand> element: recurring components are
+// expanded into individual instances clipped to [Start, End), per RFC
+// 4791 section 9.6.5. It is not part of CalendarQuery itself because not
+// every REPORT that carries a CalendarQuery supports expansion (e.g.
+// calendar-multiget never does).
+type Expand struct {
+	Start time.Time
+	End   time.Time
+}
+
+// ExpandQuery reports whether cal matches query's comp-filter and, if
+// expand is non-nil, returns cal with every recurring VEVENT/VTODO
+// expanded into concrete instances. Backends that already filter and
+// expand on their own can ignore this helper entirely; it exists so
+// that backends which just return raw stored objects still get correct
+// RFC 4791 §7.8/§9 semantics out of the box.
+func ExpandQuery(cal *ical.Calendar, query *CalendarQuery, expand *Expand) (*ical.Calendar, bool, error) {
+	if query == nil {
+		return cal, true, nil
+	}
+
+	matched, err := matchComp(cal, cal.Component, query.CompFilter)
+	if err != nil || !matched {
+		return nil, false, err
+	}
+
+	out := cal
+	if expand != nil {
+		out, err = expandRecurrences(cal, expand.Start, expand.End)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return out, true, nil
+}
+
+func matchComp(cal *ical.Calendar, comp *ical.Component, filter CompFilter) (bool, error) {
+	if comp.Name != filter.Name {
+		return filter.IsNotDefined, nil
+	}
+	if filter.IsNotDefined {
+		return false, nil
+	}
+
+	if filter.TimeRange != nil {
+		ok, err := compInTimeRange(cal, comp, *filter.TimeRange)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	for _, pf := range filter.Props {
+		ok, err := matchProp(cal, comp, pf)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	for _, cf := range filter.Comps {
+		ok, err := matchSubComp(cal, comp, cf)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// matchSubComp matches a nested comp-filter (e.g. VALARM within VEVENT)
+// against any child component with the right name.
+func matchSubComp(cal *ical.Calendar, parent *ical.Component, filter CompFilter) (bool, error) {
+	found := false
+	for _, child := range parent.Children {
+		if child.Name != filter.Name {
+			continue
+		}
+		found = true
+		ok, err := matchComp(cal, child, filter)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	if !found {
+		return filter.IsNotDefined, nil
+	}
+	return false, nil
+}
+
+func matchProp(cal *ical.Calendar, comp *ical.Component, filter PropFilter) (bool, error) {
+	prop := comp.Props.Get(filter.Name)
+	if prop == nil {
+		return filter.IsNotDefined, nil
+	}
+	if filter.IsNotDefined {
+		return false, nil
+	}
+
+	if filter.TimeRange != nil {
+		t, err := propDateTime(cal, comp, filter.Name)
+		if err != nil {
+			return false, nil
+		}
+		if t.Before(filter.TimeRange.Start) || !t.Before(filter.TimeRange.End) {
+			return false, nil
+		}
+	}
+
+	if filter.TextMatch != nil {
+		if matched := textMatch(prop.Value, *filter.TextMatch); matched == filter.TextMatch.NegateCondition {
+			return false, nil
+		}
+	}
+
+	for _, paramFilter := range filter.Params {
+		ok := matchParam(prop, paramFilter)
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func matchParam(prop *ical.Prop, filter ParamFilter) bool {
+	val := prop.Params.Get(filter.Name)
+	if val == "" {
+		return filter.IsNotDefined
+	}
+	if filter.IsNotDefined {
+		return false
+	}
+	if filter.TextMatch != nil {
+		if matched := textMatch(val, *filter.TextMatch); matched == filter.TextMatch.NegateCondition {
+			return false
+		}
+	}
+	return true
+}
+
+// textMatch implements the "i;ascii-casemap" (default) and "i;octet"
+// collations from RFC 4790. Any other collation falls back to
+// case-insensitive substring matching.
+func textMatch(value string, tm TextMatch) bool {
+	if tm.Collation == "i;octet" {
+		return strings.Contains(value, tm.Text)
+	}
+	return strings.Contains(strings.ToLower(value), strings.ToLower(tm.Text))
+}
+
+// compInTimeRange reports whether comp overlaps [r.Start, r.End),
+// expanding RRULE/RDATE occurrences when present instead of only
+// looking at the master DTSTART/DTEND. cal is used to resolve any
+// VTIMEZONE the component's DTSTART/DTEND refer to; it may be nil, in
+// which case TZID params fall back to tzdata via time.LoadLocation.
+func compInTimeRange(cal *ical.Calendar, comp *ical.Component, r TimeRange) (bool, error) {
+	instances, err := instancesInRange(cal, comp, r.Start, r.End)
+	if err != nil {
+		return false, err
+	}
+	return len(instances) > 0, nil
+}
+
+type instance struct {
+	start, end time.Time
+}
+
+// instancesInRange enumerates every occurrence of comp (applying
+// RRULE/RDATE/EXDATE) that starts before end and ends after start. The
+// literal DTSTART occurrence is only reported on its own when there's no
+// RRULE/RDATE to enumerate it; otherwise rrule-go's Set already includes
+// it, and reporting it twice would duplicate the first occurrence.
+func instancesInRange(cal *ical.Calendar, comp *ical.Component, start, end time.Time) ([]instance, error) {
+	dtstart, err := propDateTime(cal, comp, ical.PropDateTimeStart)
+	if err != nil {
+		return nil, err
+	}
+	dur := compDuration(cal, comp)
+
+	rruleProp := comp.Props.Get(ical.PropRecurrenceRule)
+	rdates := comp.Props[ical.PropRecurrenceDates]
+
+	if rruleProp == nil && len(rdates) == 0 {
+		master := instance{start: dtstart, end: dtstart.Add(dur)}
+		if overlaps(master.start, master.end, start, end) {
+			return []instance{master}, nil
+		}
+		return nil, nil
+	}
+
+	set := rrule.Set{}
+	if rruleProp != nil {
+		ro, err := rrule.StrToROption(rruleProp.Value)
+		if err != nil {
+			return nil, err
+		}
+		ro.Dtstart = dtstart
+		r, err := rrule.NewRRule(*ro)
+		if err != nil {
+			return nil, err
+		}
+		set.RRule(r)
+	} else {
+		set.RDate(dtstart)
+	}
+
+	for _, rd := range rdates {
+		t, err := rd.DateTime(time.UTC)
+		if err == nil {
+			set.RDate(t)
+		}
+	}
+	for _, ex := range comp.Props[ical.PropExceptionDates] {
+		t, err := ex.DateTime(time.UTC)
+		if err == nil {
+			set.ExDate(t)
+		}
+	}
+
+	seen := make(map[int64]struct{})
+	var out []instance
+	for _, occ := range set.Between(start.Add(-dur), end, true) {
+		if !overlaps(occ, occ.Add(dur), start, end) {
+			continue
+		}
+		key := occ.Unix()
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, instance{start: occ, end: occ.Add(dur)})
+	}
+	return out, nil
+}
+
+func overlaps(aStart, aEnd, bStart, bEnd time.Time) bool {
+	return aStart.Before(bEnd) && bStart.Before(aEnd)
+}
+
+func compDuration(cal *ical.Calendar, comp *ical.Component) time.Duration {
+	dtstart, err := propDateTime(cal, comp, ical.PropDateTimeStart)
+	if err != nil {
+		return 0
+	}
+	if dtend, err := propDateTime(cal, comp, ical.PropDateTimeEnd); err == nil {
+		return dtend.Sub(dtstart)
+	}
+	if dur := comp.Props.Get(ical.PropDuration); dur != nil {
+		if d, err := time.ParseDuration(dur.Value); err == nil {
+			return d
+		}
+	}
+	return 0
+}
+
+// propDateTime resolves name on comp, preferring the calendar's
+// VTIMEZONE definition for the property's TZID param over tzdata: many
+// clients (Outlook/Exchange in particular) emit a TZID that isn't a
+// valid IANA zone name, so looking it up with time.LoadLocation alone
+// would silently misinterpret the time or fail outright.
+func propDateTime(cal *ical.Calendar, comp *ical.Component, name string) (time.Time, error) {
+	prop := comp.Props.Get(name)
+	if prop == nil {
+		return time.Time{}, nil
+	}
+
+	if tzid := prop.Params.Get(ical.ParamTimezoneID); tzid != "" && cal != nil {
+		if l := vtimezoneLocation(cal, tzid); l != nil {
+			// Prop.DateTime loads a TZID param with time.LoadLocation
+			// itself, so parse a copy without it in the resolved zone.
+			p := *prop
+			p.Params = make(ical.Params, len(prop.Params))
+			for k, v := range prop.Params {
+				if k != ical.ParamTimezoneID {
+					p.Params[k] = v
+				}
+			}
+			return p.DateTime(l)
+		}
+	}
+	return prop.DateTime(time.UTC)
+}
+
+// vtimezoneLocation builds a *time.Location for tzid from the matching
+// VTIMEZONE component in cal, if any. The TZOFFSETTO of the first
+// STANDARD or DAYLIGHT sub-component that has one, in document order, is
+// used as a fixed offset: which observance is the most recent is not
+// considered, and DST transitions are not modeled. A TZID with no
+// matching VTIMEZONE is looked up with time.LoadLocation.
+func vtimezoneLocation(cal *ical.Calendar, tzid string) *time.Location {
+	for _, child := range cal.Children {
+		if child.Name != ical.CompTimezone {
+			continue
+		}
+		if id := child.Props.Get(ical.PropTimezoneID); id == nil || id.Value != tzid {
+			continue
+		}
+
+		for _, sub := range child.Children {
+			if sub.Name != "STANDARD" && sub.Name != "DAYLIGHT" {
+				continue
+			}
+			offset := sub.Props.Get("TZOFFSETTO")
+			if offset == nil {
+				continue
+			}
+			if secs, ok := parseUTCOffset(offset.Value); ok {
+				return time.FixedZone(tzid, secs)
+			}
+		}
+	}
+
+	if l, err := time.LoadLocation(tzid); err == nil {
+		return l
+	}
+	return nil
+}
+
+// parseUTCOffset parses a TZOFFSETTO/TZOFFSETFROM value such as "+0100"
+// or "-023016" into a signed number of seconds east of UTC.
+func parseUTCOffset(v string) (int, bool) {
+	if len(v) < 5 {
+		return 0, false
+	}
+	sign := 1
+	if v[0] == '-' {
+		sign = -1
+	} else if v[0] != '+' {
+		return 0, false
+	}
+	digits := v[1:]
+	var hh, mm, ss int
+	switch len(digits) {
+	case 4:
+		hh = int(digits[0]-'0')*10 + int(digits[1]-'0')
+		mm = int(digits[2]-'0')*10 + int(digits[3]-'0')
+	case 6:
+		hh = int(digits[0]-'0')*10 + int(digits[1]-'0')
+		mm = int(digits[2]-'0')*10 + int(digits[3]-'0')
+		ss = int(digits[4]-'0')*10 + int(digits[5]-'0')
+	default:
+		return 0, false
+	}
+	return sign * (hh*3600 + mm*60 + ss), true
+}
+
+// expandRecurrences rewrites master, recurring VEVENTs/VTODOs in cal into
+// one component of the same kind per occurrence within [start, end), each
+// with a concrete DTSTART/DTEND and no RRULE, per RFC 4791 section 9.6.5.
+// Non-recurring components are passed through unmodified. A RECURRENCE-ID
+// override is emitted unmodified in place of the occurrence whose UTC
+// start matches its RECURRENCE-ID value; an override that matches no
+// occurrence within [start, end) is left out.
+func expandRecurrences(cal *ical.Calendar, start, end time.Time) (*ical.Calendar, error) {
+	out := ical.NewCalendar()
+	out.Props = cal.Props
+
+	overrides := map[string]*ical.Component{}
+	var masters []*ical.Component
+	var passthrough []*ical.Component
+
+	for _, child := range cal.Children {
+		switch child.Name {
+		case ical.CompEvent, ical.CompToDo:
+			if rid := child.Props.Get(ical.PropRecurrenceID); rid != nil {
+				uid := child.Props.Get(ical.PropUID).Value
+				overrides[uid+"|"+rid.Value] = child
+				continue
+			}
+			masters = append(masters, child)
+		default:
+			passthrough = append(passthrough, child)
+		}
+	}
+
+	out.Children = append(out.Children, passthrough...)
+
+	for _, master := range masters {
+		if master.Props.Get(ical.PropRecurrenceRule) == nil && len(master.Props[ical.PropRecurrenceDates]) == 0 {
+			out.Children = append(out.Children, master)
+			continue
+		}
+
+		instances, err := instancesInRange(cal, master, start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		uid := master.Props.Get(ical.PropUID).Value
+		for _, inst := range instances {
+			key := uid + "|" + inst.start.UTC().Format("20060102T150405Z")
+			if override, ok := overrides[key]; ok {
+				out.Children = append(out.Children, override)
+				continue
+			}
+
+			occ := cloneComponent(master)
+			occ.Props.SetDateTime(ical.PropDateTimeStart, inst.start)
+			occ.Props.SetDateTime(ical.PropDateTimeEnd, inst.end)
+			occ.Props.Del(ical.PropRecurrenceRule)
+			occ.Props.Del(ical.PropRecurrenceDates)
+			occ.Props.Del(ical.PropExceptionDates)
+			out.Children = append(out.Children, occ)
+		}
+	}
+
+	return out, nil
+}
+
+func cloneComponent(comp *ical.Component) *ical.Component {
+	clone := ical.NewComponent(comp.Name)
+	for name, props := range comp.Props {
+		clone.Props[name] = append([]ical.Prop(nil), props...)
+	}
+	clone.Children = append([]*ical.Component(nil), comp.Children...)
+	return clone
+}
@@ -0,0 +1,104 @@
+package caldav
+
+import (
+	"testing"
+
+	"github.com/emersion/go-ical"
+)
+
+func decodeCalendarQueryTestCalendar(t *testing.T) (*ical.Calendar, *ical.Component) {
+	t.Helper()
+
+	event := ical.NewEvent()
+	event.Props.SetText(ical.PropUID, "test-event")
+	event.Props.SetText(ical.PropSummary, "Team Meeting")
+
+	attendee := ical.NewProp(ical.PropAttendee)
+	attendee.Value = "mailto:attendee@example.com"
+	attendee.Params.Set("PARTSTAT", "ACCEPTED")
+	event.Props.Add(attendee)
+
+	cal := ical.NewCalendar()
+	cal.Children = []*ical.Component{event.Component}
+	return cal, event.Component
+}
+
+var calendarQueryWithPropFilter = `
+<?xml version="1.0" encoding="UTF-8"?>
+<C:calendar-query xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">
+        <C:prop-filter name="SUMMARY">
+          <C:text-match collation="i;ascii-casemap">meeting</C:text-match>
+        </C:prop-filter>
+        <C:prop-filter name="ATTENDEE">
+          <C:param-filter name="PARTSTAT">
+            <C:text-match negate-condition="yes">NEEDS-ACTION</C:text-match>
+          </C:param-filter>
+        </C:prop-filter>
+      </C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>
+`
+
+func TestDecodeCalendarQueryPropFilter(t *testing.T) {
+	query, _, err := decodeCalendarQuery([]byte(calendarQueryWithPropFilter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(query.CompFilter.Comps) != 1 {
+		t.Fatalf("expected 1 nested comp-filter, got %d", len(query.CompFilter.Comps))
+	}
+	eventFilter := query.CompFilter.Comps[0]
+	if eventFilter.Name != "VEVENT" {
+		t.Fatalf("unexpected comp-filter name: %q", eventFilter.Name)
+	}
+	if len(eventFilter.Props) != 2 {
+		t.Fatalf("expected 2 prop-filters, got %d", len(eventFilter.Props))
+	}
+
+	summary := eventFilter.Props[0]
+	if summary.Name != "SUMMARY" {
+		t.Fatalf("unexpected prop-filter name: %q", summary.Name)
+	}
+	if summary.TextMatch == nil || summary.TextMatch.Text != "meeting" {
+		t.Fatalf("expected text-match %q, got %v", "meeting", summary.TextMatch)
+	}
+	if summary.TextMatch.Collation != "i;ascii-casemap" {
+		t.Errorf("unexpected collation: %q", summary.TextMatch.Collation)
+	}
+	if summary.TextMatch.NegateCondition {
+		t.Errorf("expected negate-condition to default to false")
+	}
+
+	attendee := eventFilter.Props[1]
+	if len(attendee.Params) != 1 {
+		t.Fatalf("expected 1 param-filter, got %d", len(attendee.Params))
+	}
+	partstat := attendee.Params[0]
+	if partstat.Name != "PARTSTAT" {
+		t.Fatalf("unexpected param-filter name: %q", partstat.Name)
+	}
+	if partstat.TextMatch == nil || !partstat.TextMatch.NegateCondition {
+		t.Fatalf("expected a negated text-match, got %v", partstat.TextMatch)
+	}
+}
+
+func TestDecodeCalendarQueryPropFilterReachesMatchProp(t *testing.T) {
+	query, _, err := decodeCalendarQuery([]byte(calendarQueryWithPropFilter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cal, _ := decodeCalendarQueryTestCalendar(t)
+	matched, err := matchComp(cal, cal.Component, query.CompFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !matched {
+		t.Error("expected the SUMMARY text-match to match the test calendar")
+	}
+}
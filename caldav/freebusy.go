@@ -0,0 +1,114 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/emersion/go-ical"
+)
+
+// FreeBusyPeriod is a single busy interval reported by
+// Backend.QueryFreeBusy.
+type FreeBusyPeriod struct {
+	Start time.Time
+	End   time.Time
+}
+
+// FreeBusyBackend is implemented by backends that support the
+// free-busy-query REPORT, per RFC 4791 section 7.10.
+type FreeBusyBackend interface {
+	QueryFreeBusy(ctx context.Context, path string, start, end time.Time) ([]FreeBusyPeriod, error)
+}
+
+// BusyPeriodsFromCalendar expands every VEVENT in cal that overlaps
+// [start, end), skipping those that can't affect availability
+// (TRANSP:TRANSPARENT or STATUS:CANCELLED), and returns the resulting
+// busy periods merged and sorted.
+func BusyPeriodsFromCalendar(cal *ical.Calendar, start, end time.Time) ([]FreeBusyPeriod, error) {
+	var periods []FreeBusyPeriod
+
+	for _, comp := range cal.Children {
+		if comp.Name != ical.CompEvent {
+			continue
+		}
+		if transp := comp.Props.Get(ical.PropTransparency); transp != nil && transp.Value == "TRANSPARENT" {
+			continue
+		}
+		if status := comp.Props.Get(ical.PropStatus); status != nil && status.Value == "CANCELLED" {
+			continue
+		}
+
+		instances, err := instancesInRange(cal, comp, start, end)
+		if err != nil {
+			return nil, err
+		}
+		for _, inst := range instances {
+			s, e := inst.start, inst.end
+			if s.Before(start) {
+				s = start
+			}
+			if e.After(end) {
+				e = end
+			}
+			if s.Before(e) {
+				periods = append(periods, FreeBusyPeriod{Start: s, End: e})
+			}
+		}
+	}
+
+	return mergePeriods(periods), nil
+}
+
+// mergePeriods sorts periods by start time and coalesces any that
+// overlap or touch.
+func mergePeriods(periods []FreeBusyPeriod) []FreeBusyPeriod {
+	if len(periods) == 0 {
+		return nil
+	}
+
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].Start.Before(periods[j].Start)
+	})
+
+	merged := []FreeBusyPeriod{periods[0]}
+	for _, p := range periods[1:] {
+		last := &merged[len(merged)-1]
+		if !p.Start.After(last.End) {
+			if p.End.After(last.End) {
+				last.End = p.End
+			}
+			continue
+		}
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// FreeBusyObject builds a VFREEBUSY component covering [start, end) with
+// one FREEBUSY line per busy period, ready to be served as
+// text/calendar.
+func FreeBusyObject(start, end time.Time, periods []FreeBusyPeriod) *ical.Calendar {
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropProductID, "-//emersion/go-webdav//CalDAV//EN")
+
+	fb := ical.NewComponent(ical.CompFreeBusy)
+	fb.Props.SetDateTime(ical.PropDateTimeStart, start)
+	fb.Props.SetDateTime(ical.PropDateTimeEnd, end)
+	fb.Props.SetDateTime(ical.PropDateTimeStamp, time.Now().UTC())
+
+	for _, p := range periods {
+		prop := ical.NewProp(ical.PropFreeBusy)
+		prop.Value = fmt.Sprintf("%s/%s", formatUTC(p.Start), formatUTC(p.End))
+		fb.Props.Add(prop)
+	}
+
+	cal.Children = append(cal.Children, fb)
+	return cal
+}
+
+func formatUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
@@ -0,0 +1,64 @@
+package caldav
+
+import (
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+)
+
+func newBusyEvent(uid string, start, end time.Time) *ical.Component {
+	event := ical.NewEvent()
+	event.Props.SetText(ical.PropUID, uid)
+	event.Props.SetDateTime(ical.PropDateTimeStamp, start)
+	event.Props.SetDateTime(ical.PropDateTimeStart, start)
+	event.Props.SetDateTime(ical.PropDateTimeEnd, end)
+	return event.Component
+}
+
+func TestBusyPeriodsFromCalendar(t *testing.T) {
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 1, 17, 0, 0, 0, time.UTC)
+
+	busy := newBusyEvent("busy", time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC))
+
+	transparent := newBusyEvent("transparent", time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 13, 0, 0, 0, time.UTC))
+	transparent.Props.SetText(ical.PropTransparency, "TRANSPARENT")
+
+	cancelled := newBusyEvent("cancelled", time.Date(2026, 1, 1, 14, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 15, 0, 0, 0, time.UTC))
+	cancelled.Props.SetText(ical.PropStatus, "CANCELLED")
+
+	cal := ical.NewCalendar()
+	cal.Children = []*ical.Component{busy, transparent, cancelled}
+
+	periods, err := BusyPeriodsFromCalendar(cal, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 1 {
+		t.Fatalf("expected 1 busy period, got %d: %v", len(periods), periods)
+	}
+	if !periods[0].Start.Equal(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected busy period start: %v", periods[0].Start)
+	}
+	if !periods[0].End.Equal(time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected busy period end: %v", periods[0].End)
+	}
+}
+
+func TestMergePeriods(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	periods := []FreeBusyPeriod{
+		{Start: base.Add(2 * time.Hour), End: base.Add(3 * time.Hour)},
+		{Start: base, End: base.Add(1 * time.Hour)},
+		{Start: base.Add(1 * time.Hour), End: base.Add(2 * time.Hour)},
+	}
+
+	merged := mergePeriods(periods)
+	if len(merged) != 1 {
+		t.Fatalf("expected adjacent periods to merge into 1, got %d: %v", len(merged), merged)
+	}
+	if !merged[0].Start.Equal(base) || !merged[0].End.Equal(base.Add(3*time.Hour)) {
+		t.Errorf("unexpected merged period: %v", merged[0])
+	}
+}
@@ -0,0 +1,27 @@
+package caldav
+
+// Filter returns the subset of cos that matches query's comp-filter. It
+// is used by simple Backend implementations that don't want to evaluate
+// comp/prop/param filters themselves: they can return every stored
+// object from QueryCalendarObjects and let Filter (and, for time-range
+// and <C:expand> semantics, ExpandQuery) narrow the results down.
+func Filter(query *CalendarQuery, cos []CalendarObject) ([]CalendarObject, error) {
+	if query == nil {
+		return cos, nil
+	}
+
+	var out []CalendarObject
+	for _, co := range cos {
+		if co.Data == nil {
+			continue
+		}
+		matched, err := matchComp(co.Data, co.Data.Component, query.CompFilter)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			out = append(out, co)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,127 @@
+package caldav
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav/internal"
+)
+
+// Scheduling methods understood by the iTIP dispatcher. These mirror the
+// METHOD property values defined by RFC 5546.
+const (
+	ScheduleMethodRequest = "REQUEST"
+	ScheduleMethodReply   = "REPLY"
+	ScheduleMethodCancel  = "CANCEL"
+)
+
+// ScheduleResponse is the per-recipient outcome of a scheduling POST to
+// the Outbox collection, reported back as a <C:schedule-response>
+// element per RFC 6638 section 3.4.1.
+type ScheduleResponse struct {
+	Recipient    string
+	Status       string
+	CalendarData *ical.Calendar
+}
+
+// SchedulingPrincipal holds the schedule-inbox-URL, schedule-outbox-URL
+// and calendar-user-address-set values exposed on principal resources
+// via PROPFIND so that clients can
+// discover where to deliver and receive iTIP messages, per RFC 6638
+// section 2.1.
+type SchedulingPrincipal struct {
+	InboxURL              string
+	OutboxURL             string
+	CalendarUserAddresses []string
+}
+
+// scheduleInboxURLName and friends are the CalDAV property names used in
+// PROPFIND responses against a principal resource.
+var (
+	scheduleInboxURLName        = xml.Name{Space: "urn:ietf:params:xml:ns:caldav", Local: "schedule-inbox-URL"}
+	scheduleOutboxURLName       = xml.Name{Space: "urn:ietf:params:xml:ns:caldav", Local: "schedule-outbox-URL"}
+	calendarUserAddressSetName  = xml.Name{Space: "urn:ietf:params:xml:ns:caldav", Local: "calendar-user-address-set"}
+	calendarAutoScheduleFeature = "calendar-auto-schedule"
+)
+
+// scheduleInboxURL, scheduleOutboxURL and calendarUserAddressSet are the
+// PROPFIND element bodies for the property names above.
+type scheduleInboxURL struct {
+	XMLName xml.Name      `xml:"urn:ietf:params:xml:ns:caldav schedule-inbox-URL"`
+	Href    internal.Href `xml:"href"`
+}
+
+type scheduleOutboxURL struct {
+	XMLName xml.Name      `xml:"urn:ietf:params:xml:ns:caldav schedule-outbox-URL"`
+	Href    internal.Href `xml:"href"`
+}
+
+type calendarUserAddressSet struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:caldav calendar-user-address-set"`
+	Hrefs   []string `xml:"href"`
+}
+
+// ScheduleDeliver hands an iTIP message to a single attendee's Inbox.
+// Backends implementing scheduling support store msg as a new object in
+// the Inbox collection belonging to to, and are responsible for any
+// downstream notification (push, email fallback, etc).
+//
+// ScheduleSend dispatches msg (an iTIP REQUEST, REPLY or CANCEL) from
+// the organizer or attendee identified by from to every recipient found
+// in the message's ATTENDEE (or, for a REPLY, ORGANIZER) properties,
+// returning one ScheduleResponse per recipient.
+//
+// SchedulingPrincipal returns the Inbox/Outbox URLs and calendar user
+// addresses to advertise on a PROPFIND against the principal at
+// principalPath, per RFC 6638 section 2.1.
+type SchedulingBackend interface {
+	ScheduleSend(ctx context.Context, from string, msg *ical.Calendar) ([]ScheduleResponse, error)
+	ScheduleDeliver(ctx context.Context, to string, msg *ical.Calendar) error
+	SchedulingPrincipal(ctx context.Context, principalPath string) (*SchedulingPrincipal, error)
+}
+
+// HandleScheduleOutbox processes a POST to a principal's Outbox
+// collection: it checks that msg's iTIP METHOD is REQUEST, REPLY or
+// CANCEL and passes msg to backend.ScheduleSend, which resolves the
+// recipients. The caller is responsible
+// for encoding the returned responses as a <C:schedule-response> body.
+func HandleScheduleOutbox(ctx context.Context, backend SchedulingBackend, from string, msg *ical.Calendar) ([]ScheduleResponse, error) {
+	method := msg.Props.Get(ical.PropMethod)
+	if method == nil {
+		return nil, fmt.Errorf("caldav: scheduling message is missing METHOD")
+	}
+
+	switch method.Value {
+	case ScheduleMethodRequest, ScheduleMethodReply, ScheduleMethodCancel:
+	default:
+		return nil, fmt.Errorf("caldav: unsupported scheduling method %q", method.Value)
+	}
+
+	return backend.ScheduleSend(ctx, from, msg)
+}
+
+// deliverToAttendees is invoked after a PUT of a calendar object that
+// carries ATTENDEE properties: it delivers msg to each attendee's Inbox
+// unless suppressReply is set (the client sent "Schedule-Reply: F").
+func deliverToAttendees(ctx context.Context, backend SchedulingBackend, comp *ical.Component, msg *ical.Calendar, suppressReply bool) ([]ScheduleResponse, error) {
+	if suppressReply {
+		return nil, nil
+	}
+
+	var responses []ScheduleResponse
+	for _, attendee := range comp.Props[ical.PropAttendee] {
+		err := backend.ScheduleDeliver(ctx, attendee.Value, msg)
+		status := "2.0;Success"
+		if err != nil {
+			status = "5.1;Service unavailable"
+		}
+		responses = append(responses, ScheduleResponse{
+			Recipient: attendee.Value,
+			Status:    status,
+		})
+		attendee.Params.Set("SCHEDULE-STATUS", status)
+	}
+	return responses, nil
+}
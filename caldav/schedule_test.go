@@ -0,0 +1,71 @@
+package caldav
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-ical"
+)
+
+type schedulingTestBackend struct {
+	testBackend
+	principal SchedulingPrincipal
+}
+
+func (b *schedulingTestBackend) ScheduleSend(ctx context.Context, from string, msg *ical.Calendar) ([]ScheduleResponse, error) {
+	return nil, nil
+}
+
+func (b *schedulingTestBackend) ScheduleDeliver(ctx context.Context, to string, msg *ical.Calendar) error {
+	return nil
+}
+
+func (b *schedulingTestBackend) SchedulingPrincipal(ctx context.Context, principalPath string) (*SchedulingPrincipal, error) {
+	return &b.principal, nil
+}
+
+func TestPropFindRootSchedulingPrincipal(t *testing.T) {
+	backend := &schedulingTestBackend{
+		principal: SchedulingPrincipal{
+			InboxURL:              "/user/inbox/",
+			OutboxURL:             "/user/outbox/",
+			CalendarUserAddresses: []string{"mailto:user@example.com"},
+		},
+	}
+
+	req := httptest.NewRequest("PROPFIND", "/", strings.NewReader(`
+<?xml version="1.0" encoding="UTF-8"?>
+<A:propfind xmlns:A="DAV:" xmlns:B="urn:ietf:params:xml:ns:caldav">
+  <A:prop>
+    <B:schedule-inbox-URL/>
+    <B:schedule-outbox-URL/>
+    <B:calendar-user-address-set/>
+  </A:prop>
+</A:propfind>
+`))
+	req.Header.Set("Content-Type", "application/xml")
+	w := httptest.NewRecorder()
+	handler := Handler{Backend: backend}
+	handler.ServeHTTP(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := string(data)
+
+	for _, want := range []string{
+		"<schedule-inbox-URL xmlns=\"urn:ietf:params:xml:ns:caldav\"><href>/user/inbox/</href></schedule-inbox-URL>",
+		"<schedule-outbox-URL xmlns=\"urn:ietf:params:xml:ns:caldav\"><href>/user/outbox/</href></schedule-outbox-URL>",
+		"<calendar-user-address-set xmlns=\"urn:ietf:params:xml:ns:caldav\"><href>mailto:user@example.com</href></calendar-user-address-set>",
+	} {
+		if !strings.Contains(resp, want) {
+			t.Errorf("expected %q in response:\n%s", want, resp)
+		}
+	}
+}
@@ -0,0 +1,899 @@
+package caldav
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav/internal"
+)
+
+// Backend is implemented by CalDAV servers to provide calendars and
+// calendar objects.
+type Backend interface {
+	CreateCalendar(ctx context.Context, calendar *Calendar) error
+	ListCalendars(ctx context.Context) ([]Calendar, error)
+	GetCalendar(ctx context.Context, path string) (*Calendar, error)
+	CalendarHomeSetPath(ctx context.Context) (string, error)
+	CurrentUserPrincipal(ctx context.Context) (string, error)
+	DeleteCalendarObject(ctx context.Context, path string) error
+	GetCalendarObject(ctx context.Context, path string, req *CalendarCompRequest) (*CalendarObject, error)
+	PutCalendarObject(ctx context.Context, path string, calendar *ical.Calendar, opts *PutCalendarObjectOptions) (*CalendarObject, error)
+	ListCalendarObjects(ctx context.Context, path string, req *CalendarCompRequest) ([]CalendarObject, error)
+	QueryCalendarObjects(ctx context.Context, path string, query *CalendarQuery) ([]CalendarObject, error)
+}
+
+// Handler handles CalDAV HTTP requests using the provided Backend.
+type Handler struct {
+	Backend Backend
+}
+
+// calendarDescriptionName, calendarTimezoneName and calendarColorName
+// are the CalDAV/Apple property names surfaced by PROPFIND.
+var (
+	calendarDescriptionName = xml.Name{Space: "urn:ietf:params:xml:ns:caldav", Local: "calendar-description"}
+	calendarTimezoneName    = xml.Name{Space: "urn:ietf:params:xml:ns:caldav", Local: "calendar-timezone"}
+	calendarColorName       = xml.Name{Space: "http://apple.com/ns/ical/", Local: "calendar-color"}
+	supportedCompSetName    = xml.Name{Space: "urn:ietf:params:xml:ns:caldav", Local: "supported-calendar-component-set"}
+	currentUserPrincipal    = xml.Name{Space: "DAV:", Local: "current-user-principal"}
+	principalURLName        = xml.Name{Space: "DAV:", Local: "principal-URL"}
+	resourceTypeName        = xml.Name{Space: "DAV:", Local: "resourcetype"}
+)
+
+type calendarDescription struct {
+	XMLName     xml.Name `xml:"urn:ietf:params:xml:ns:caldav calendar-description"`
+	Description string   `xml:",chardata"`
+}
+
+type calendarTimezone struct {
+	XMLName  xml.Name `xml:"urn:ietf:params:xml:ns:caldav calendar-timezone"`
+	Timezone string   `xml:",chardata"`
+}
+
+type calendarColor struct {
+	XMLName xml.Name `xml:"http://apple.com/ns/ical/ calendar-color"`
+	Color   string   `xml:",chardata"`
+}
+
+// backend adapts a Backend into the internal HTTP plumbing, carrying the
+// URL prefix the Handler is mounted under.
+type backend struct {
+	Backend Backend
+	Prefix  string
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Backend == nil {
+		http.Error(w, "caldav: no backend available", http.StatusInternalServerError)
+		return
+	}
+
+	b := backend{Backend: h.Backend}
+	ctx := r.Context()
+
+	switch r.Method {
+	case "PROPFIND":
+		b.handlePropFind(ctx, w, r)
+	case "REPORT":
+		b.handleReport(ctx, w, r)
+	case "MKCOL":
+		if err := b.Mkcol(r); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	case "PUT":
+		b.handlePut(ctx, w, r)
+	case "POST":
+		b.handlePost(ctx, w, r)
+	case "OPTIONS":
+		b.handleOptions(w, r)
+	default:
+		http.Error(w, "caldav: unsupported method", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleOptions advertises the CalDAV features this Handler supports via
+// the DAV: header, including calendar-auto-schedule and
+// calendar-managed-attachments when the Backend implements the
+// corresponding interface.
+func (b *backend) handleOptions(w http.ResponseWriter, r *http.Request) {
+	features := "1, 2, 3, calendar-access"
+	if _, ok := b.Backend.(SchedulingBackend); ok {
+		features += ", " + calendarAutoScheduleFeature
+	}
+	if _, ok := b.Backend.(AttachmentBackend); ok {
+		features += ", " + calendarManagedAttachmentsFeature
+	}
+	w.Header().Set("DAV", features)
+	w.WriteHeader(http.StatusOK)
+}
+
+// handlePut stores the PUT body as a calendar object and, when the
+// Backend supports scheduling and the object carries ATTENDEE
+// properties, delivers an iTIP message to each attendee's Inbox per RFC
+// 6638 section 3.2.1. A client sends "Schedule-Reply: F" to suppress
+// this delivery.
+func (b *backend) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	cal, err := ical.NewDecoder(r.Body).Decode()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	co, err := b.Backend.PutCalendarObject(ctx, r.URL.Path, cal, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if sched, ok := b.Backend.(SchedulingBackend); ok {
+		suppressReply := strings.EqualFold(r.Header.Get("Schedule-Reply"), "F")
+		for _, comp := range cal.Children {
+			if len(comp.Props[ical.PropAttendee]) == 0 {
+				continue
+			}
+			if _, err := deliverToAttendees(ctx, sched, comp, cal, suppressReply); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	if co != nil && co.ETag != "" {
+		w.Header().Set("ETag", co.ETag)
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (b *backend) handlePropFind(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var pf internal.PropFind
+	if err := xml.NewDecoder(r.Body).Decode(&pf); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Path == "/" || r.URL.Path == "" {
+		b.serveRootPropFind(ctx, w, pf)
+		return
+	}
+
+	cal, err := b.Backend.GetCalendar(ctx, r.URL.Path)
+	if err != nil {
+		b.serveCollectionPropFind(ctx, w, r, pf)
+		return
+	}
+
+	resp, err := b.propFindCalendar(ctx, cal, pf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resps := []internal.Response{*resp}
+	if requestDepth(r) != internal.DepthZero {
+		if objs, err := b.Backend.ListCalendarObjects(ctx, cal.Path, nil); err == nil {
+			for _, obj := range objs {
+				resps = append(resps, *propFindCalendarObject(&obj, pf))
+			}
+		}
+	}
+	internal.ServeMultiStatus(w, internal.NewMultiStatus(resps...))
+}
+
+// requestDepth parses the Depth header, defaulting to DepthInfinity per
+// RFC 4918 section 10.2 when the header is absent or malformed.
+func requestDepth(r *http.Request) internal.Depth {
+	if d, err := internal.ParseDepth(r.Header.Get("Depth")); err == nil {
+		return d
+	}
+	return internal.DepthInfinity
+}
+
+var calendarDataName = xml.Name{Space: "urn:ietf:params:xml:ns:caldav", Local: "calendar-data"}
+
+// propFindCalendarObject builds the PROPFIND response for a single
+// calendar object nested under a collection with Depth 1 or infinity.
+func propFindCalendarObject(obj *CalendarObject, pf internal.PropFind) *internal.Response {
+	resp := internal.NewOKResponse(obj.Path)
+	for _, name := range propNames(pf) {
+		switch name {
+		case internal.GetETagName:
+			if obj.ETag != "" {
+				resp.EncodeProp(http.StatusOK, &internal.GetETag{ETag: internal.ETag(obj.ETag)})
+			}
+		case internal.GetContentLengthName:
+			resp.EncodeProp(http.StatusOK, &internal.GetContentLength{Length: obj.ContentLength})
+		case internal.ResourceTypeName:
+			resp.EncodeProp(http.StatusOK, internal.NewResourceType())
+		case calendarDataName:
+			if obj.Data != nil {
+				resp.EncodeProp(http.StatusOK, &calendarData{Data: obj.Data})
+			}
+		}
+	}
+	return resp
+}
+
+// propNames returns the xml.Name of every property explicitly requested
+// by pf.Prop. It returns nil for an allprop/propname request, since the
+// properties below are only ever encoded when named explicitly.
+func propNames(pf internal.PropFind) []xml.Name {
+	if pf.Prop == nil {
+		return nil
+	}
+	names := make([]xml.Name, 0, len(pf.Prop.Raw))
+	for _, raw := range pf.Prop.Raw {
+		if name, ok := raw.XMLName(); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+type principalURL struct {
+	XMLName xml.Name      `xml:"DAV: principal-URL"`
+	Href    internal.Href `xml:"href"`
+}
+
+func (b *backend) serveRootPropFind(ctx context.Context, w http.ResponseWriter, pf internal.PropFind) {
+	principal, err := b.Backend.CurrentUserPrincipal(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var sched *SchedulingPrincipal
+	if sb, ok := b.Backend.(SchedulingBackend); ok {
+		sched, _ = sb.SchedulingPrincipal(ctx, principal)
+	}
+
+	resp := internal.NewOKResponse("/")
+	for _, name := range propNames(pf) {
+		switch name {
+		case currentUserPrincipal:
+			resp.EncodeProp(http.StatusOK, &internal.CurrentUserPrincipal{Href: internal.Href{Path: principal}})
+		case principalURLName:
+			resp.EncodeProp(http.StatusOK, &principalURL{Href: internal.Href{Path: principal}})
+		case resourceTypeName:
+			resp.EncodeProp(http.StatusOK, internal.NewResourceType(internal.CollectionName))
+		case scheduleInboxURLName:
+			if sched != nil {
+				resp.EncodeProp(http.StatusOK, &scheduleInboxURL{Href: internal.Href{Path: sched.InboxURL}})
+			}
+		case scheduleOutboxURLName:
+			if sched != nil {
+				resp.EncodeProp(http.StatusOK, &scheduleOutboxURL{Href: internal.Href{Path: sched.OutboxURL}})
+			}
+		case calendarUserAddressSetName:
+			if sched != nil {
+				resp.EncodeProp(http.StatusOK, &calendarUserAddressSet{Hrefs: sched.CalendarUserAddresses})
+			}
+		}
+	}
+
+	internal.ServeMultiStatus(w, internal.NewMultiStatus(*resp))
+}
+
+func (b *backend) serveCollectionPropFind(ctx context.Context, w http.ResponseWriter, r *http.Request, pf internal.PropFind) {
+	calendars, err := b.Backend.ListCalendars(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var resps []internal.Response
+	for _, cal := range calendars {
+		if !strings.HasPrefix(cal.Path, r.URL.Path) {
+			continue
+		}
+		resp, err := b.propFindCalendar(ctx, &cal, pf)
+		if err != nil {
+			continue
+		}
+		resps = append(resps, *resp)
+	}
+
+	internal.ServeMultiStatus(w, internal.NewMultiStatus(resps...))
+}
+
+func (b *backend) propFindCalendar(ctx context.Context, cal *Calendar, pf internal.PropFind) (*internal.Response, error) {
+	compSet := cal.SupportedComponentSet
+	if len(compSet) == 0 {
+		compSet = []string{"VEVENT"}
+	}
+
+	resp := internal.NewOKResponse(cal.Path)
+	for _, name := range propNames(pf) {
+		switch name {
+		case internal.DisplayNameName:
+			resp.EncodeProp(http.StatusOK, &internal.DisplayName{Name: cal.Name})
+		case calendarDescriptionName:
+			resp.EncodeProp(http.StatusOK, &calendarDescription{Description: cal.Description})
+		case calendarTimezoneName:
+			resp.EncodeProp(http.StatusOK, &calendarTimezone{Timezone: cal.Timezone})
+		case calendarColorName:
+			resp.EncodeProp(http.StatusOK, &calendarColor{Color: cal.Color})
+		case supportedCompSetName:
+			resp.EncodeProp(http.StatusOK, &supportedCalendarComponentSet{Comp: compSet})
+		case getctagName:
+			if sync, ok := b.Backend.(SyncCollectionBackend); ok {
+				if ctag, err := CTag(ctx, sync, cal.Path); err == nil {
+					resp.EncodeProp(http.StatusOK, &getctag{CTag: ctag})
+				}
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+type getctag struct {
+	XMLName xml.Name `xml:"http://calendarserver.org/ns/ getctag"`
+	CTag    string   `xml:",chardata"`
+}
+
+type supportedCalendarComponentSet struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set"`
+	Comp    []string `xml:"comp>name"`
+}
+
+// handleReport dispatches a REPORT request to the calendar-query,
+// calendar-multiget, free-busy-query or sync-collection implementation,
+// per RFC 4791 section 7 and RFC 6578 section 3.2.
+func (b *backend) handleReport(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var probe struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &probe); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch probe.XMLName.Local {
+	case "calendar-query":
+		b.handleQuery(ctx, w, r, data)
+	case "calendar-multiget":
+		b.handleMultiget(ctx, w, r, data)
+	case "sync-collection":
+		b.handleSyncCollection(ctx, w, r, data)
+	case "free-busy-query":
+		b.handleFreeBusyQuery(ctx, w, r, data)
+	default:
+		http.Error(w, "caldav: unsupported REPORT", http.StatusBadRequest)
+	}
+}
+
+// handleSyncCollection serves a sync-collection REPORT. If the supplied
+// sync-token is invalid, it reports a DAV:valid-sync-token precondition
+// failure so the client falls back to a full PROPFIND.
+func (b *backend) handleSyncCollection(ctx context.Context, w http.ResponseWriter, r *http.Request, body []byte) {
+	sync, ok := b.Backend.(SyncCollectionBackend)
+	if !ok {
+		http.Error(w, "caldav: backend does not support sync-collection", http.StatusForbidden)
+		return
+	}
+
+	query, err := decodeSyncCollectionQuery(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := HandleSyncCollection(ctx, sync, r.URL.Path, query)
+	if err != nil {
+		if err == ErrInvalidSyncToken {
+			writeValidSyncTokenError(w)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var resps []internal.Response
+	for _, href := range resp.Added {
+		co, err := b.Backend.GetCalendarObject(ctx, href, nil)
+		if err != nil || co.Data == nil {
+			continue
+		}
+		resps = append(resps, *calendarObjectResponse(co.Path, co.Data))
+	}
+	for _, href := range resp.Modified {
+		co, err := b.Backend.GetCalendarObject(ctx, href, nil)
+		if err != nil || co.Data == nil {
+			continue
+		}
+		resps = append(resps, *calendarObjectResponse(co.Path, co.Data))
+	}
+	for _, href := range resp.Removed {
+		resps = append(resps, *internal.NewErrorResponse(href, internal.HTTPErrorf(http.StatusNotFound, "removed")))
+	}
+
+	ms := internal.NewMultiStatus(resps...)
+	ms.SyncToken = resp.Token
+	internal.ServeMultiStatus(w, ms)
+}
+
+// validSyncToken is the DAV:valid-sync-token precondition element
+// reported when a sync-collection REPORT's sync-token is unknown or has
+// expired, per RFC 6578 section 3.2.
+type validSyncToken struct {
+	XMLName xml.Name `xml:"DAV: valid-sync-token"`
+}
+
+// writeValidSyncTokenError reports the DAV:valid-sync-token precondition
+// failure as a 403 Forbidden <D:error> body, so the client knows to fall
+// back to a full PROPFIND instead of retrying the same sync-token.
+func writeValidSyncTokenError(w http.ResponseWriter) {
+	raw, err := internal.EncodeRawXMLElement(&validSyncToken{})
+	if err != nil {
+		http.Error(w, ErrInvalidSyncToken.Error(), http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+	xml.NewEncoder(w).Encode(&internal.Error{Raw: []internal.RawXMLValue{*raw}})
+}
+
+func decodeSyncCollectionQuery(body []byte) (SyncCollectionQuery, error) {
+	var req struct {
+		XMLName   xml.Name `xml:"sync-collection"`
+		SyncToken string   `xml:"sync-token"`
+		SyncLevel string   `xml:"sync-level"`
+		Limit     struct {
+			NResults int `xml:"nresults"`
+		} `xml:"limit"`
+	}
+	if err := xml.Unmarshal(body, &req); err != nil {
+		return SyncCollectionQuery{}, err
+	}
+	return SyncCollectionQuery{
+		SyncToken: req.SyncToken,
+		SyncLevel: req.SyncLevel,
+		Limit:     req.Limit.NResults,
+	}, nil
+}
+
+// handleQuery serves a calendar-query REPORT: it asks the Backend for
+// every candidate object, then applies ExpandQuery so that backends
+// which don't understand RRULE/time-range filtering still get correct
+// results.
+func (b *backend) handleQuery(ctx context.Context, w http.ResponseWriter, r *http.Request, body []byte) {
+	query, expand, err := decodeCalendarQuery(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cos, err := b.Backend.QueryCalendarObjects(ctx, r.URL.Path, query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var resps []internal.Response
+	for _, co := range cos {
+		if co.Data == nil {
+			continue
+		}
+		out, matched, err := ExpandQuery(co.Data, query, expand)
+		if err != nil || !matched {
+			continue
+		}
+		resps = append(resps, *calendarObjectResponse(co.Path, out))
+	}
+
+	internal.ServeMultiStatus(w, internal.NewMultiStatus(resps...))
+}
+
+func (b *backend) handleMultiget(ctx context.Context, w http.ResponseWriter, r *http.Request, body []byte) {
+	hrefs, err := decodeCalendarMultiget(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var resps []internal.Response
+	for _, href := range hrefs {
+		co, err := b.Backend.GetCalendarObject(ctx, href, nil)
+		if err != nil || co.Data == nil {
+			continue
+		}
+		resps = append(resps, *calendarObjectResponse(co.Path, co.Data))
+	}
+
+	internal.ServeMultiStatus(w, internal.NewMultiStatus(resps...))
+}
+
+func calendarObjectResponse(path string, cal *ical.Calendar) *internal.Response {
+	resp := internal.NewOKResponse(path)
+	resp.EncodeProp(http.StatusOK, &calendarData{Data: cal})
+	return resp
+}
+
+type calendarData struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+	Data    *ical.Calendar
+}
+
+func (cd *calendarData) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	var buf strings.Builder
+	if cd.Data != nil {
+		if err := ical.NewEncoder(&buf).Encode(cd.Data); err != nil {
+			return err
+		}
+	}
+	start.Name = calendarDataName
+	return e.EncodeElement(buf.String(), start)
+}
+
+// decodeCalendarQuery parses a <C:calendar-query> REPORT body into a
+// CalendarQuery and, if present, an Expand.
+func decodeCalendarQuery(body []byte) (*CalendarQuery, *Expand, error) {
+	var req struct {
+		XMLName xml.Name `xml:"calendar-query"`
+		Prop    struct {
+			CalendarData struct {
+				Expand *xmlTimeRange `xml:"expand"`
+			} `xml:"calendar-data"`
+		} `xml:"prop"`
+		Filter struct {
+			CompFilter xmlCompFilter `xml:"comp-filter"`
+		} `xml:"filter"`
+	}
+	if err := xml.Unmarshal(body, &req); err != nil {
+		return nil, nil, err
+	}
+
+	query := &CalendarQuery{CompFilter: req.Filter.CompFilter.toCompFilter()}
+
+	var expand *Expand
+	if x := req.Prop.CalendarData.Expand; x != nil {
+		tr, err := x.toTimeRange()
+		if err != nil {
+			return nil, nil, err
+		}
+		expand = &Expand{Start: tr.Start, End: tr.End}
+	}
+	return query, expand, nil
+}
+
+// xmlCompFilter is the raw XML shape of a <C:comp-filter>; toCompFilter
+// converts it into the richer CompFilter used by ExpandQuery.
+type xmlCompFilter struct {
+	Name         string          `xml:"name,attr"`
+	IsNotDefined *struct{}       `xml:"is-not-defined"`
+	TimeRange    *xmlTimeRange   `xml:"time-range"`
+	PropFilters  []xmlPropFilter `xml:"prop-filter"`
+	CompFilters  []xmlCompFilter `xml:"comp-filter"`
+}
+
+// xmlPropFilter is the raw XML shape of a <C:prop-filter>.
+type xmlPropFilter struct {
+	Name         string           `xml:"name,attr"`
+	IsNotDefined *struct{}        `xml:"is-not-defined"`
+	TimeRange    *xmlTimeRange    `xml:"time-range"`
+	TextMatch    *xmlTextMatch    `xml:"text-match"`
+	ParamFilters []xmlParamFilter `xml:"param-filter"`
+}
+
+// xmlParamFilter is the raw XML shape of a <C:param-filter>.
+type xmlParamFilter struct {
+	Name         string        `xml:"name,attr"`
+	IsNotDefined *struct{}     `xml:"is-not-defined"`
+	TextMatch    *xmlTextMatch `xml:"text-match"`
+}
+
+// xmlTextMatch is the raw XML shape of a <C:text-match>.
+type xmlTextMatch struct {
+	Text            string `xml:",chardata"`
+	Collation       string `xml:"collation,attr"`
+	NegateCondition string `xml:"negate-condition,attr"`
+}
+
+type xmlTimeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+func (f xmlCompFilter) toCompFilter() CompFilter {
+	out := CompFilter{Name: f.Name, IsNotDefined: f.IsNotDefined != nil}
+	if f.TimeRange != nil {
+		if tr, err := f.TimeRange.toTimeRange(); err == nil {
+			out.TimeRange = tr
+		}
+	}
+	for _, pf := range f.PropFilters {
+		out.Props = append(out.Props, pf.toPropFilter())
+	}
+	for _, cf := range f.CompFilters {
+		out.Comps = append(out.Comps, cf.toCompFilter())
+	}
+	return out
+}
+
+func (f xmlPropFilter) toPropFilter() PropFilter {
+	out := PropFilter{
+		Name:         f.Name,
+		IsNotDefined: f.IsNotDefined != nil,
+		TextMatch:    f.TextMatch.toTextMatch(),
+	}
+	if f.TimeRange != nil {
+		if tr, err := f.TimeRange.toTimeRange(); err == nil {
+			out.TimeRange = tr
+		}
+	}
+	for _, paramf := range f.ParamFilters {
+		out.Params = append(out.Params, paramf.toParamFilter())
+	}
+	return out
+}
+
+func (f xmlParamFilter) toParamFilter() ParamFilter {
+	return ParamFilter{
+		Name:         f.Name,
+		IsNotDefined: f.IsNotDefined != nil,
+		TextMatch:    f.TextMatch.toTextMatch(),
+	}
+}
+
+func (tm *xmlTextMatch) toTextMatch() *TextMatch {
+	if tm == nil {
+		return nil
+	}
+	return &TextMatch{
+		Text:            tm.Text,
+		Collation:       tm.Collation,
+		NegateCondition: tm.NegateCondition == "yes",
+	}
+}
+
+func (tr xmlTimeRange) toTimeRange() (*TimeRange, error) {
+	const layout = "20060102T150405Z"
+	start, err := time.Parse(layout, tr.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse(layout, tr.End)
+	if err != nil {
+		return nil, err
+	}
+	return &TimeRange{Start: start, End: end}, nil
+}
+
+func decodeCalendarMultiget(body []byte) ([]string, error) {
+	var req struct {
+		XMLName xml.Name `xml:"calendar-multiget"`
+		Hrefs   []string `xml:"href"`
+	}
+	if err := xml.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	return req.Hrefs, nil
+}
+
+// Mkcol handles a MKCOL request that creates a new calendar collection,
+// parsing the displayname/calendar-description/calendar-color/
+// calendar-timezone/supported-calendar-component-set properties out of
+// the request body.
+func (b *backend) Mkcol(r *http.Request) error {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		XMLName xml.Name `xml:"mkcol"`
+		Set     struct {
+			Prop struct {
+				DisplayName string `xml:"displayname"`
+				Description string `xml:"calendar-description"`
+				Color       string `xml:"calendar-color"`
+				Timezone    string `xml:"calendar-timezone"`
+				CompSet     struct {
+					Comp []struct {
+						Name string `xml:"name,attr"`
+					} `xml:"comp"`
+				} `xml:"supported-calendar-component-set"`
+			} `xml:"prop"`
+		} `xml:"set"`
+	}
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	compSet := []string{}
+	for _, c := range req.Set.Prop.CompSet.Comp {
+		compSet = append(compSet, c.Name)
+	}
+
+	cal := &Calendar{
+		Path:                  r.URL.Path,
+		Name:                  req.Set.Prop.DisplayName,
+		Description:           req.Set.Prop.Description,
+		Color:                 strings.TrimSpace(req.Set.Prop.Color),
+		Timezone:              strings.TrimSpace(req.Set.Prop.Timezone),
+		SupportedComponentSet: compSet,
+	}
+
+	return b.Backend.CreateCalendar(r.Context(), cal)
+}
+
+// handlePost routes a POST request to the Managed Attachments handler
+// when an "action" query parameter is present (RFC 8607 section 3), and
+// otherwise to the Outbox scheduling handler. A POST that the Backend
+// has no matching capability for is rejected.
+func (b *backend) handlePost(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("action") {
+		attach, ok := b.Backend.(AttachmentBackend)
+		if !ok {
+			http.Error(w, "caldav: backend does not support managed attachments", http.StatusForbidden)
+			return
+		}
+		b.handleAttachmentPost(ctx, w, r, attach)
+		return
+	}
+
+	sched, ok := b.Backend.(SchedulingBackend)
+	if !ok {
+		http.Error(w, "caldav: backend does not support scheduling", http.StatusForbidden)
+		return
+	}
+	b.handleScheduleOutboxPost(ctx, w, r, sched)
+}
+
+// handleAttachmentPost serves a Managed Attachments POST against a
+// calendar object, dispatching via HandleAttachmentAction and returning
+// the updated object as calendar-data.
+func (b *backend) handleAttachmentPost(ctx context.Context, w http.ResponseWriter, r *http.Request, backend AttachmentBackend) {
+	att := Attachment{
+		ContentType:        r.Header.Get("Content-Type"),
+		ContentDisposition: r.Header.Get("Content-Disposition"),
+		Body:               r.Body,
+		Size:               r.ContentLength,
+	}
+
+	obj, err := HandleAttachmentAction(ctx, backend, r.URL.Path, r.URL.Query(), att)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if obj.ETag != "" {
+		w.Header().Set("ETag", obj.ETag)
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	ical.NewEncoder(w).Encode(obj.Data)
+}
+
+// handleScheduleOutboxPost serves a POST to a principal's schedule
+// Outbox: it decodes the iTIP message, uses the Backend's
+// CurrentUserPrincipal as the originator, dispatches via
+// HandleScheduleOutbox, and encodes the results as a
+// <C:schedule-response> body.
+func (b *backend) handleScheduleOutboxPost(ctx context.Context, w http.ResponseWriter, r *http.Request, sched SchedulingBackend) {
+	cal, err := ical.NewDecoder(r.Body).Decode()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, err := b.Backend.CurrentUserPrincipal(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	responses, err := HandleScheduleOutbox(ctx, sched, from, cal)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeScheduleResponse(w, responses)
+}
+
+// scheduleResponseXML is the <C:schedule-response> body returned for a
+// scheduling POST, per RFC 6638 section 3.4.1.
+type scheduleResponseXML struct {
+	XMLName   xml.Name               `xml:"urn:ietf:params:xml:ns:caldav schedule-response"`
+	Responses []scheduleResponseItem `xml:"response"`
+}
+
+type scheduleResponseItem struct {
+	Recipient     string `xml:"recipient>href"`
+	RequestStatus string `xml:"request-status"`
+}
+
+func writeScheduleResponse(w http.ResponseWriter, responses []ScheduleResponse) {
+	body := scheduleResponseXML{}
+	for _, resp := range responses {
+		body.Responses = append(body.Responses, scheduleResponseItem{
+			Recipient:     resp.Recipient,
+			RequestStatus: resp.Status,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := xml.NewEncoder(w).Encode(body); err != nil {
+		return
+	}
+}
+
+// handleFreeBusyQuery serves a free-busy-query REPORT against a calendar
+// collection, per RFC 4791 section 7.10: the response is a single
+// text/calendar VFREEBUSY body, not a multistatus. If the Backend
+// implements FreeBusyBackend, its QueryFreeBusy is used directly;
+// otherwise every calendar object in the collection is expanded with
+// BusyPeriodsFromCalendar and the results are merged.
+func (b *backend) handleFreeBusyQuery(ctx context.Context, w http.ResponseWriter, r *http.Request, body []byte) {
+	start, end, err := decodeFreeBusyQuery(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var periods []FreeBusyPeriod
+	if fb, ok := b.Backend.(FreeBusyBackend); ok {
+		periods, err = fb.QueryFreeBusy(ctx, r.URL.Path, start, end)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		cos, err := b.Backend.ListCalendarObjects(ctx, r.URL.Path, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, co := range cos {
+			if co.Data == nil {
+				continue
+			}
+			ps, err := BusyPeriodsFromCalendar(co.Data, start, end)
+			if err != nil {
+				continue
+			}
+			periods = append(periods, ps...)
+		}
+		periods = mergePeriods(periods)
+	}
+
+	cal := FreeBusyObject(start, end, periods)
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	ical.NewEncoder(w).Encode(cal)
+}
+
+func decodeFreeBusyQuery(body []byte) (start, end time.Time, err error) {
+	var req struct {
+		XMLName   xml.Name      `xml:"free-busy-query"`
+		TimeRange *xmlTimeRange `xml:"time-range"`
+	}
+	if err := xml.Unmarshal(body, &req); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if req.TimeRange == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("caldav: free-busy-query is missing time-range")
+	}
+	tr, err := req.TimeRange.toTimeRange()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return tr.Start, tr.End, nil
+}
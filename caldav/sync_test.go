@@ -0,0 +1,54 @@
+package caldav
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type syncTestBackend struct {
+	testBackend
+	err error
+}
+
+func (b *syncTestBackend) SyncCollection(ctx context.Context, path string, token string, limit int) (*SyncResponse, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &SyncResponse{Token: "sync-token-1"}, nil
+}
+
+func (b *syncTestBackend) CurrentSyncToken(ctx context.Context, path string) (string, error) {
+	return "sync-token-1", nil
+}
+
+func TestSyncCollectionInvalidToken(t *testing.T) {
+	backend := &syncTestBackend{err: ErrInvalidSyncToken}
+	handler := Handler{Backend: backend}
+
+	req := httptest.NewRequest("REPORT", "/user/calendars/cal", strings.NewReader(`
+<?xml version="1.0" encoding="UTF-8"?>
+<D:sync-collection xmlns:D="DAV:">
+  <D:sync-token>stale-token</D:sync-token>
+  <D:sync-level>1</D:sync-level>
+</D:sync-collection>
+`))
+	req.Header.Set("Content-Type", "application/xml")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+	if res.StatusCode != 403 {
+		t.Fatalf("expected 403 Forbidden, got %d", res.StatusCode)
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `<valid-sync-token xmlns="DAV:">`) {
+		t.Errorf("expected DAV:valid-sync-token precondition element in body:\n%s", string(data))
+	}
+}